@@ -0,0 +1,103 @@
+package gdg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+)
+
+// dziXmlns is the XML namespace Deep Zoom descriptors declare.
+const dziXmlns = "http://schemas.microsoft.com/deepzoom/2008"
+
+// dziSize is the <Size> element of a DZI descriptor.
+type dziSize struct {
+	Width  uint `xml:"Width,attr" json:"Width"`
+	Height uint `xml:"Height,attr" json:"Height"`
+}
+
+// dziDescriptor mirrors the XML structure of a `*.dzi` descriptor.
+type dziDescriptor struct {
+	XMLName  xml.Name `xml:"Image"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	TileSize uint     `xml:"TileSize,attr"`
+	Overlap  uint     `xml:"Overlap,attr"`
+	Format   string   `xml:"Format,attr"`
+	Size     dziSize  `xml:"Size"`
+}
+
+// dziJSON mirrors the `Image` envelope some viewers accept as a JSON
+// alternative to the XML descriptor.
+type dziJSON struct {
+	Image struct {
+		Xmlns    string  `json:"xmlns"`
+		TileSize uint    `json:"TileSize"`
+		Overlap  uint    `json:"Overlap"`
+		Format   string  `json:"Format"`
+		Size     dziSize `json:"Size"`
+	} `json:"Image"`
+}
+
+// filesDirPath returns the directory the tile pyramid is laid out under,
+// following the `<Name>_files` convention Deep Zoom viewers expect.
+func filesDirPath(opt *Option) string {
+	return fmt.Sprintf("%s/%s_files", opt.DirPath, opt.Name)
+}
+
+// WriteDZI writes the XML `<DirPath>/<Name>.dzi` descriptor through opt.Saver.
+func WriteDZI(opt *Option) error {
+	d := dziDescriptor{
+		Xmlns:    dziXmlns,
+		TileSize: opt.TileSize,
+		Overlap:  opt.Overlap,
+		Format:   string(opt.Format),
+		Size:     dziSize{Width: opt.Width, Height: opt.Height},
+	}
+
+	buffer := bytes.Buffer{}
+	buffer.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buffer)
+	enc.Indent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/%s.dzi", opt.DirPath, opt.Name)
+	return opt.Saver.SaveFile(path, &buffer)
+}
+
+// WriteDZIJSON writes the JSON variant of the descriptor as
+// `<DirPath>/<Name>.js` through opt.Saver, for viewers that consume DZI-JSON.
+func WriteDZIJSON(opt *Option) error {
+	var d dziJSON
+	d.Image.Xmlns = dziXmlns
+	d.Image.TileSize = opt.TileSize
+	d.Image.Overlap = opt.Overlap
+	d.Image.Format = string(opt.Format)
+	d.Image.Size = dziSize{Width: opt.Width, Height: opt.Height}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("%s/%s.js", opt.DirPath, opt.Name)
+	return opt.Saver.SaveFile(path, bytes.NewReader(data))
+}
+
+// GenerateDZI generates a complete Deep Zoom Image: the tile pyramid laid
+// out under `<DirPath>/<Name>_files/<level>/<col>_<row>.<format>`, the XML
+// `<Name>.dzi` descriptor and its `<Name>.js` JSON twin.
+func GenerateDZI(m *image.NRGBA, opt *Option) error {
+	if err := WriteDZI(opt); err != nil {
+		return err
+	}
+	if err := WriteDZIJSON(opt); err != nil {
+		return err
+	}
+
+	tileOpt := *opt
+	tileOpt.DirPath = filesDirPath(opt)
+	return Generate(m, &tileOpt)
+}
@@ -10,7 +10,7 @@ import (
     "io"
 	"math"
 	"runtime"
-	"sync"
+	"sync/atomic"
 
 	"github.com/disintegration/imaging"
 )
@@ -20,6 +20,7 @@ type ImageFormat string
 const (
 	JPEG ImageFormat = "jpeg"
 	PNG  ImageFormat = "png"
+	WEBP ImageFormat = "webp"
 )
 
 type Saver interface {
@@ -30,10 +31,21 @@ type Saver interface {
 type Option struct {
 	DirPath       string
     Saver         Saver
+	Name          string
 	Format        ImageFormat
 	Overlap       uint
 	TileSize      uint
 	Width, Height uint
+	// WebPQuality and WebPLossless are only consulted when Format is WEBP.
+	WebPQuality  float32
+	WebPLossless bool
+	// Concurrency is the number of tiles cropped, encoded and saved at
+	// once. Zero defaults to runtime.NumCPU().
+	Concurrency int
+	// Progress, if set, is called after each tile of a level is
+	// successfully saved. It is invoked concurrently from the worker
+	// pool, so implementations must be safe for concurrent use.
+	Progress func(level, tileIndex, totalTiles uint)
 }
 
 // GetMaxLevel computes and returns the maximum level of DZI files
@@ -78,25 +90,28 @@ func ComputeTileRect(opt *Option, col, row, maxCol, maxRow uint) (rect image.Rec
 }
 
 // SaveTile saves tile to given path based on level, column and row.
-func SaveTile(dirPath string, saver Saver, level, col, row uint, format ImageFormat, m *image.NRGBA, wg *sync.WaitGroup) error {
-	defer wg.Done()
-	defer runtime.GC()
+func SaveTile(opt *Option, level, col, row uint, m *image.NRGBA) error {
     var err error
 
-	imgPath := fmt.Sprintf("%s/%d/%d_%d.%s", dirPath, level, col, row, format)
+	imgPath := fmt.Sprintf("%s/%d/%d_%d.%s", opt.DirPath, level, col, row, opt.Format)
     buffer := bytes.Buffer{}
 
-	switch format {
+	switch opt.Format {
 	case JPEG:
 		err = jpeg.Encode(&buffer, m, &jpeg.Options{jpeg.DefaultQuality})
 	case PNG:
 		err = png.Encode(&buffer, m)
+	case WEBP:
+		if webpEncoder == nil {
+			return fmt.Errorf("gdg: WEBP format requested but no WebPEncoder registered, see RegisterWebPEncoder")
+		}
+		err = webpEncoder.Encode(&buffer, m, opt.WebPQuality, opt.WebPLossless)
 	}
     if err != nil {
         return err
     }
 
-    err = saver.SaveFile(imgPath, &buffer)
+    err = opt.Saver.SaveFile(imgPath, &buffer)
     if err != nil {
         return err
     }
@@ -106,35 +121,57 @@ func SaveTile(dirPath string, saver Saver, level, col, row uint, format ImageFor
 
 // Generate generates DZI files of given image and option.
 // Width and height in option and image should be same.
+//
+// The pyramid is built from the highest level (the full-resolution image)
+// down to level 0 (a single 1x1 tile): level maxLevel is tiled from m
+// directly, and each lower level is tiled from a thumbnail of the level
+// above it rather than from m itself.
 func Generate(m *image.NRGBA, opt *Option) error {
-	level := GetMaxLevel(opt.Width, opt.Height)
-	wg := &sync.WaitGroup{}
-	tm := m
+	maxLevel := GetMaxLevel(opt.Width, opt.Height)
+	concurrency := opt.Concurrency
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
 
-	var col, row uint
-	for ; level >= 0; level-- {
-		cols, rows := GetLevelGrids(level, opt.Width, opt.Height, opt.TileSize)
-		wg.Add(int(cols * rows))
-		for col = 0; col < cols; col++ {
-			for row = 0; row < rows; row++ {
-				go SaveTile(opt.DirPath, opt.Saver, level, col, row, opt.Format,
-					imaging.Crop(tm, ComputeTileRect(opt, col, row, cols, rows)), wg)
-				// if err := SaveTile(opt.DirPath, level, col, row, opt.Format,
-				// 	imaging.Crop(tm, ComputeTileRect(opt, col, row, cols, rows))); err != nil {
-				// 	return err
-				// }
+	tm := m
+	width, height := opt.Width, opt.Height
+
+	for level := int(maxLevel); level >= 0; level-- {
+		levelOpt := *opt
+		levelOpt.Width, levelOpt.Height = width, height
+		lvl, src := uint(level), tm
+
+		cols, rows := GetLevelGrids(lvl, width, height, opt.TileSize)
+		totalTiles := cols * rows
+		var done uint32
+		lg := NewLimitGroup(concurrency)
+		for col := uint(0); col < cols; col++ {
+			for row := uint(0); row < rows; row++ {
+				col, row := col, row
+				lg.Go(func() error {
+					rect := ComputeTileRect(&levelOpt, col, row, cols, rows)
+					if err := SaveTile(&levelOpt, lvl, col, row, imaging.Crop(src, rect)); err != nil {
+						return err
+					}
+					if opt.Progress != nil {
+						opt.Progress(lvl, uint(atomic.AddUint32(&done, 1)), totalTiles)
+					}
+					return nil
+				})
 			}
 		}
+		if err := lg.Wait(); err != nil {
+			return err
+		}
 
-		opt.Width = uint(math.Ceil(float64(opt.Width) / 2))
-		opt.Height = uint(math.Ceil(float64(opt.Height) / 2))
-		tm = imaging.Thumbnail(tm, int(opt.Width), int(opt.Height), imaging.Box)
-		runtime.GC()
 		if level == 0 {
 			break
 		}
+
+		width = uint(math.Ceil(float64(width) / 2))
+		height = uint(math.Ceil(float64(height) / 2))
+		tm = imaging.Thumbnail(tm, int(width), int(height), imaging.Box)
 	}
 
-	wg.Wait()
 	return nil
 }
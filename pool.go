@@ -0,0 +1,66 @@
+package gdg
+
+import (
+	"fmt"
+	"sync"
+)
+
+// LimitGroup runs submitted jobs across a fixed number of worker goroutines
+// and reports the first error any of them returns.
+type LimitGroup struct {
+	jobs    chan func() error
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewLimitGroup starts a LimitGroup backed by concurrency worker
+// goroutines. concurrency is clamped to at least 1.
+func NewLimitGroup(concurrency int) *LimitGroup {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	lg := &LimitGroup{jobs: make(chan func() error)}
+	lg.wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go lg.worker()
+	}
+
+	return lg
+}
+
+func (lg *LimitGroup) worker() {
+	defer lg.wg.Done()
+	for job := range lg.jobs {
+		if err := runJob(job); err != nil {
+			lg.errOnce.Do(func() { lg.err = err })
+		}
+	}
+}
+
+// runJob runs job, recovering a panic into an error so a single bad job
+// is reported through Wait like any other failure instead of taking down
+// the whole worker pool, and with it the calling program.
+func runJob(job func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("gdg: panic in tile job: %v", r)
+		}
+	}()
+	return job()
+}
+
+// Go submits job to be run by one of the pool's workers. It blocks until a
+// worker is free to accept it.
+func (lg *LimitGroup) Go(job func() error) {
+	lg.jobs <- job
+}
+
+// Wait closes the job queue and blocks until every submitted job has run,
+// returning the first error encountered, if any.
+func (lg *LimitGroup) Wait() error {
+	close(lg.jobs)
+	lg.wg.Wait()
+	return lg.err
+}
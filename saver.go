@@ -0,0 +1,110 @@
+package gdg
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileSaver is a Saver that writes tiles to the local filesystem. Parent
+// directories are created as needed, and each file is written to a
+// temporary sibling and renamed into place so readers never observe a
+// partially written tile.
+type FileSaver struct{}
+
+// SaveFile writes the contents of r to path.
+func (FileSaver) SaveFile(path string, r io.Reader) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".gdg-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// MemorySaver is a Saver that keeps every file in memory, keyed by the
+// path it was saved under. It is useful in tests and for serving a
+// pyramid straight out of an HTTP handler without touching disk.
+type MemorySaver struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemorySaver returns an empty MemorySaver.
+func NewMemorySaver() *MemorySaver {
+	return &MemorySaver{files: make(map[string][]byte)}
+}
+
+// SaveFile reads r fully and stores it under path.
+func (s *MemorySaver) SaveFile(path string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = data
+	return nil
+}
+
+// File returns the bytes saved under path, if any.
+func (s *MemorySaver) File(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[path]
+	return data, ok
+}
+
+// ZipSaver is a Saver that writes every tile and the DZI descriptor into
+// a single zip archive, mirroring the single-file DZI container some
+// deep-zoom tooling ships instead of a loose directory tree. Close must
+// be called once all files have been saved to finalize the archive.
+type ZipSaver struct {
+	mu sync.Mutex
+	zw *zip.Writer
+}
+
+// NewZipSaver returns a ZipSaver that writes its archive to w.
+func NewZipSaver(w io.Writer) *ZipSaver {
+	return &ZipSaver{zw: zip.NewWriter(w)}
+}
+
+// SaveFile adds path as an entry in the archive with the contents of r.
+func (s *ZipSaver) SaveFile(path string, r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.zw.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// Close finalizes the zip archive's central directory. It must be called
+// after the last SaveFile call.
+func (s *ZipSaver) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.zw.Close()
+}
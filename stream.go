@@ -0,0 +1,266 @@
+package gdg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/disintegration/imaging"
+)
+
+// downscaleStripRows is the number of destination rows produced per strip
+// when spilling a level to the cache. It bounds the amount of source
+// pixel data materialized at once when moving from one pyramid level to
+// the next.
+const downscaleStripRows = 256
+
+// TileSource is an image that can be read one region at a time without
+// requiring the whole image to be decoded into memory up front.
+type TileSource interface {
+	Bounds() image.Rectangle
+	SubImage(r image.Rectangle) image.Image
+}
+
+// NRGBASource adapts an *image.NRGBA to TileSource, for callers that
+// already hold the full image decoded and want to keep using GenerateStream.
+type NRGBASource struct {
+	Img *image.NRGBA
+}
+
+// Bounds returns the bounds of the wrapped image.
+func (s NRGBASource) Bounds() image.Rectangle {
+	return s.Img.Bounds()
+}
+
+// SubImage returns the region of the wrapped image covered by r.
+func (s NRGBASource) SubImage(r image.Rectangle) image.Image {
+	return imaging.Crop(s.Img, r)
+}
+
+// cacheStrip records where one contiguous band of a cached level's rows
+// was spilled to, in a raw row-major NRGBA file written through a Saver.
+// The backing file is opened once, on first read, and reused for every
+// later SubImage call that overlaps it rather than reopened each time.
+type cacheStrip struct {
+	path     string
+	rowStart uint
+	rows     uint
+
+	openOnce sync.Once
+	openErr  error
+	file     *os.File
+}
+
+func (s *cacheStrip) open() (*os.File, error) {
+	s.openOnce.Do(func() {
+		s.file, s.openErr = os.Open(s.path)
+	})
+	return s.file, s.openErr
+}
+
+// cachedLevelSource is a TileSource backed by a pyramid level spilled to
+// disk one strip at a time by spillLevel. SubImage reads back only the
+// rows and columns a request actually covers, so the level's pixels are
+// never resident in memory as a whole - only whatever region a caller
+// asks for at once.
+type cachedLevelSource struct {
+	strips []*cacheStrip
+	width  uint
+	height uint
+}
+
+// Close releases the open file handles backing c's strips. It must be
+// called once c is no longer needed as a tile or downscale source.
+func (c *cachedLevelSource) Close() error {
+	var firstErr error
+	for _, strip := range c.strips {
+		if strip.file == nil {
+			continue
+		}
+		if err := strip.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *cachedLevelSource) Bounds() image.Rectangle {
+	return image.Rect(0, 0, int(c.width), int(c.height))
+}
+
+func (c *cachedLevelSource) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(c.Bounds())
+	dst := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+
+	for _, strip := range c.strips {
+		lo, hi := int(strip.rowStart), int(strip.rowStart+strip.rows)
+		overlapLo, overlapHi := r.Min.Y, r.Max.Y
+		if lo > overlapLo {
+			overlapLo = lo
+		}
+		if hi < overlapHi {
+			overlapHi = hi
+		}
+		if overlapLo >= overlapHi {
+			continue
+		}
+
+		err := copyStripRows(strip, c.width, overlapLo-lo, overlapHi-lo, r.Min.X, r.Max.X, dst, overlapLo-r.Min.Y)
+		if err != nil {
+			// TileSource.SubImage has no error return, and a failure here
+			// means the cache file gdg itself wrote is missing or
+			// truncated - an environment/bug condition, not bad input.
+			panic(fmt.Errorf("gdg: reading cached level strip %q: %w", strip.path, err))
+		}
+	}
+
+	return dst
+}
+
+// copyStripRows reads the [fromRow, toRow) rows and [colMin, colMax)
+// columns of strip's raw NRGBA pixels and copies them into dst starting
+// at dstRowOffset. It reads directly at the needed byte offsets, via the
+// strip's single shared file handle, rather than reading the strip file
+// in full or reopening it per call.
+func copyStripRows(strip *cacheStrip, width uint, fromRow, toRow, colMin, colMax int, dst *image.NRGBA, dstRowOffset int) error {
+	f, err := strip.open()
+	if err != nil {
+		return err
+	}
+
+	rowBytes := int(width) * 4
+	buf := make([]byte, (colMax-colMin)*4)
+	for row := fromRow; row < toRow; row++ {
+		if _, err := f.ReadAt(buf, int64(row*rowBytes+colMin*4)); err != nil {
+			return err
+		}
+		copy(dst.Pix[dst.PixOffset(0, dstRowOffset+(row-fromRow)):], buf)
+	}
+
+	return nil
+}
+
+// spillLevel downscales source (width x height) to half resolution and
+// writes the result to cacheDir through cache, one bounded-size strip at
+// a time, box filtering 2x2 blocks of source per strip. Neither the
+// source level nor the downscaled level is ever assembled in memory as a
+// whole; the returned cachedLevelSource reads the result back on demand.
+func spillLevel(source TileSource, width, height uint, cache Saver, cacheDir string, level int) (*cachedLevelSource, uint, uint, error) {
+	bounds := source.Bounds()
+	dstWidth := uint(math.Ceil(float64(width) / 2))
+	dstHeight := uint(math.Ceil(float64(height) / 2))
+
+	cached := &cachedLevelSource{width: dstWidth, height: dstHeight}
+
+	for dstY := uint(0); dstY < dstHeight; dstY += downscaleStripRows {
+		stripHeight := uint(downscaleStripRows)
+		if dstY+stripHeight > dstHeight {
+			stripHeight = dstHeight - dstY
+		}
+
+		srcRect := image.Rect(bounds.Min.X, bounds.Min.Y+int(dstY)*2, bounds.Max.X, bounds.Min.Y+int(dstY+stripHeight)*2)
+		if srcRect.Max.Y > bounds.Max.Y {
+			srcRect.Max.Y = bounds.Max.Y
+		}
+
+		stripThumb := imaging.Thumbnail(source.SubImage(srcRect), int(dstWidth), int(stripHeight), imaging.Box)
+
+		path := fmt.Sprintf("%s/level-%d-strip-%d.raw", cacheDir, level, dstY)
+		if err := cache.SaveFile(path, bytes.NewReader(stripThumb.Pix)); err != nil {
+			return nil, 0, 0, err
+		}
+
+		cached.strips = append(cached.strips, &cacheStrip{path: path, rowStart: dstY, rows: stripHeight})
+	}
+
+	return cached, dstWidth, dstHeight, nil
+}
+
+// GenerateStream generates a DZI pyramid for src the same way Generate
+// does, but it is bounded-memory regardless of image size: a level is
+// tiled region-by-region straight out of its TileSource, and the
+// downscaled image for the level below is spilled to a temp, Saver-backed
+// cache one strip at a time (see spillLevel) rather than kept resident as
+// a decoded level image. At most one strip's worth of pixels - not a
+// whole level, let alone the whole source - is ever held in memory at
+// once.
+func GenerateStream(src TileSource, opt *Option) error {
+	maxLevel := GetMaxLevel(opt.Width, opt.Height)
+	concurrency := opt.Concurrency
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
+
+	cacheDir, err := os.MkdirTemp("", "gdg-stream-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(cacheDir)
+	cache := FileSaver{}
+
+	var cur TileSource = src
+	width, height := opt.Width, opt.Height
+
+	// openCache is the cachedLevelSource currently serving as a tile or
+	// downscale source, if any; it is closed as soon as a newer one
+	// replaces it, or when GenerateStream returns.
+	var openCache *cachedLevelSource
+	defer func() {
+		if openCache != nil {
+			openCache.Close()
+		}
+	}()
+
+	for level := int(maxLevel); level >= 0; level-- {
+		levelOpt := *opt
+		levelOpt.Width, levelOpt.Height = width, height
+		lvl, source := uint(level), cur
+		origin := source.Bounds().Min
+
+		cols, rows := GetLevelGrids(lvl, width, height, opt.TileSize)
+		totalTiles := cols * rows
+		var done uint32
+		lg := NewLimitGroup(concurrency)
+		for col := uint(0); col < cols; col++ {
+			for row := uint(0); row < rows; row++ {
+				col, row := col, row
+				lg.Go(func() error {
+					rect := ComputeTileRect(&levelOpt, col, row, cols, rows).Add(origin)
+					tile := imaging.Clone(source.SubImage(rect))
+					if err := SaveTile(&levelOpt, lvl, col, row, tile); err != nil {
+						return err
+					}
+					if opt.Progress != nil {
+						opt.Progress(lvl, uint(atomic.AddUint32(&done, 1)), totalTiles)
+					}
+					return nil
+				})
+			}
+		}
+		if err := lg.Wait(); err != nil {
+			return err
+		}
+
+		if level == 0 {
+			break
+		}
+
+		cached, newWidth, newHeight, err := spillLevel(source, width, height, cache, cacheDir, level-1)
+		if err != nil {
+			return err
+		}
+		if openCache != nil {
+			openCache.Close()
+		}
+		openCache = cached
+		width, height = newWidth, newHeight
+		cur = cached
+	}
+
+	return nil
+}
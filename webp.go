@@ -0,0 +1,25 @@
+package gdg
+
+import (
+	"image"
+	"io"
+)
+
+// WebPEncoder encodes an image to WebP. gdg never imports a WebP encoder
+// itself so that the module's required dependencies stay minimal; callers
+// that want WEBP tiles register an encoder backed by, for example,
+// github.com/chai2010/webp or a cgo libwebp binding.
+type WebPEncoder interface {
+	Encode(w io.Writer, m image.Image, quality float32, lossless bool) error
+}
+
+// webpEncoder is the encoder used for the WEBP format, set via
+// RegisterWebPEncoder.
+var webpEncoder WebPEncoder
+
+// RegisterWebPEncoder installs the WebPEncoder used whenever Option.Format
+// is WEBP. SaveTile returns an error if WEBP is requested before an encoder
+// is registered.
+func RegisterWebPEncoder(enc WebPEncoder) {
+	webpEncoder = enc
+}